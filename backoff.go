@@ -0,0 +1,42 @@
+package machine
+
+import (
+	"math/rand"
+	"time"
+)
+
+// expBackoff computes jittered exponential backoff delays: the exponential delay is bounded by a
+// min and max duration, and jitter then scales it to between half and all of that value.
+type expBackoff struct {
+	min, max time.Duration
+	attempt  int
+}
+
+func newExpBackoff(min, max time.Duration) *expBackoff {
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max < min {
+		max = min
+	}
+	return &expBackoff{min: min, max: max}
+}
+
+// next returns the delay to wait before the next attempt, and advances the backoff.
+func (b *expBackoff) next() time.Duration {
+	d := b.min
+	if b.attempt > 0 && b.attempt < 32 {
+		if scaled := b.min << uint(b.attempt); scaled > 0 && scaled < b.max {
+			d = scaled
+		} else {
+			d = b.max
+		}
+	}
+	b.attempt++
+	return time.Duration(float64(d) * (0.5 + rand.Float64()*0.5))
+}
+
+// reset clears the attempt count, e.g. after a successful run.
+func (b *expBackoff) reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,259 @@
+package machine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Cache is a concurrency-safe key/value store scoped to a Machine, closed alongside it. Bind
+// layers in-flight deduplication and memoization on top: concurrent callers binding the same key
+// share one computation.
+type Cache interface {
+	Get(key any) (any, bool)
+	Set(key any, value any)
+	Delete(key any)
+	Bind(key any, fn func(ctx context.Context) (any, error)) *Handle
+	NewGeneration(name string) *Generation
+	Stats() CacheStats
+	Close()
+}
+
+// CacheStats is a snapshot of a Cache's memoization activity.
+type CacheStats struct {
+	Hits     int64
+	Misses   int64
+	Inflight int64
+}
+
+type handleState int
+
+const (
+	handleIdle handleState = iota
+	handleRunning
+	handleDone
+	handleFailed
+)
+
+// handle is the shared state backing one or more Handles bound to the same key.
+type handle struct {
+	mu    sync.Mutex
+	state handleState
+	done  chan struct{}
+	value any
+	err   error
+	fn    func(ctx context.Context) (any, error)
+	refs  map[*Generation]struct{}
+}
+
+// Handle is a memoized, in-flight-deduplicated computation bound to a cache key.
+type Handle struct {
+	key   any
+	cache *cache
+	h     *handle
+}
+
+// Get returns the handle's cached value, computing it at most once across every caller sharing
+// this key. Cancelling ctx only unblocks this caller; it never cancels the shared computation.
+func (h *Handle) Get(ctx context.Context) (any, error) {
+	h.h.mu.Lock()
+	switch h.h.state {
+	case handleDone, handleFailed:
+		value, err := h.h.value, h.h.err
+		h.h.mu.Unlock()
+		atomic.AddInt64(&h.cache.hits, 1)
+		return value, err
+	case handleIdle:
+		h.h.state = handleRunning
+		h.h.mu.Unlock()
+		atomic.AddInt64(&h.cache.misses, 1)
+		h.cache.compute(h.h)
+	default:
+		h.h.mu.Unlock()
+	}
+	select {
+	case <-h.h.done:
+		h.h.mu.Lock()
+		value, err := h.h.value, h.h.err
+		h.h.mu.Unlock()
+		return value, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Cached reports whether the handle's computation has already finished.
+func (h *Handle) Cached() bool {
+	h.h.mu.Lock()
+	defer h.h.mu.Unlock()
+	return h.h.state == handleDone || h.h.state == handleFailed
+}
+
+type cache struct {
+	data *sync.Map
+
+	machine *Machine
+
+	mu      sync.Mutex
+	handles map[any]*handle
+
+	hits, misses, inflight int64
+}
+
+func (c *cache) Get(key any) (any, bool) {
+	return c.data.Load(key)
+}
+
+func (c *cache) Set(key any, value any) {
+	c.data.Store(key, value)
+}
+
+func (c *cache) Delete(key any) {
+	c.data.Delete(key)
+}
+
+// Bind returns the Handle for key, creating its shared computation from fn if this is the first
+// caller to bind it. Later binds of the same key reuse the in-flight or cached result and ignore
+// their own fn.
+func (c *cache) Bind(key any, fn func(ctx context.Context) (any, error)) *Handle {
+	h := c.bind(key, fn, nil)
+	return &Handle{key: key, cache: c, h: h}
+}
+
+// bind looks up or creates the handle for key and, if g is non-nil, registers g as a referrer of
+// it, all while holding c.mu.
+func (c *cache) bind(key any, fn func(ctx context.Context) (any, error), g *Generation) *handle {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.handles == nil {
+		c.handles = map[any]*handle{}
+	}
+	h, ok := c.handles[key]
+	if !ok {
+		h = &handle{done: make(chan struct{}), fn: fn}
+		c.handles[key] = h
+	}
+	if g != nil {
+		c.ref(key, h, g)
+	}
+	return h
+}
+
+// compute runs h.fn exactly once, through Machine.Go when the Cache is owned by a Machine so the
+// work counts against max and is cancelled at Machine.Close.
+func (c *cache) compute(h *handle) {
+	atomic.AddInt64(&c.inflight, 1)
+	run := func(ctx context.Context) {
+		value, err := h.fn(ctx)
+		h.mu.Lock()
+		h.value, h.err = value, err
+		if err != nil {
+			h.state = handleFailed
+		} else {
+			h.state = handleDone
+		}
+		h.mu.Unlock()
+		close(h.done)
+		atomic.AddInt64(&c.inflight, -1)
+	}
+	if c.machine != nil && c.machine.ctx.Err() == nil {
+		c.machine.Go(func(r Routine) error {
+			run(r.Context())
+			return nil
+		})
+		return
+	}
+	// Either there's no owning Machine, or it's already cancelled and Go would silently drop the
+	// work (leaving h.done unclosed forever): run it directly so the Handle still resolves.
+	ctx := context.Background()
+	if c.machine != nil {
+		ctx = c.machine.ctx
+	}
+	go run(ctx)
+}
+
+// ref registers g as a referrer of the handle bound to key, keeping it alive while g exists.
+func (c *cache) ref(key any, h *handle, g *Generation) {
+	h.mu.Lock()
+	if h.refs == nil {
+		h.refs = map[*Generation]struct{}{}
+	}
+	h.refs[g] = struct{}{}
+	h.mu.Unlock()
+}
+
+// unref drops g's reference to key, evicting the handle once no generation references it. c.mu is
+// held across the whole refcount-check-then-delete so a concurrent Generation.Bind (which must
+// also take c.mu, via bind, before it can add a ref via h.mu) can't race the eviction: it either
+// completes entirely before this call observes the handle empty, or entirely after, never in
+// between.
+func (c *cache) unref(key any, g *Generation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h, ok := c.handles[key]
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	delete(h.refs, g)
+	empty := len(h.refs) == 0
+	h.mu.Unlock()
+	if empty && c.handles[key] == h {
+		delete(c.handles, key)
+	}
+}
+
+// NewGeneration returns a new Generation scoping Handles bound through it.
+func (c *cache) NewGeneration(name string) *Generation {
+	return &Generation{name: name, cache: c, keys: map[any]struct{}{}}
+}
+
+func (c *cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:     atomic.LoadInt64(&c.hits),
+		Misses:   atomic.LoadInt64(&c.misses),
+		Inflight: atomic.LoadInt64(&c.inflight),
+	}
+}
+
+func (c *cache) Close() {
+	c.data.Range(func(key, _ any) bool {
+		c.data.Delete(key)
+		return true
+	})
+}
+
+// Generation scopes a set of Cache Handles. Destroying it drops its references to every Handle
+// it bound; a Handle is evicted once no Generation references it any longer.
+type Generation struct {
+	name  string
+	cache *cache
+
+	mu        sync.Mutex
+	keys      map[any]struct{}
+	destroyed bool
+}
+
+// Bind returns the Handle for key, registering this Generation as one of its referrers.
+func (g *Generation) Bind(key any, fn func(ctx context.Context) (any, error)) *Handle {
+	h := g.cache.bind(key, fn, g)
+	g.mu.Lock()
+	if !g.destroyed {
+		g.keys[key] = struct{}{}
+	}
+	g.mu.Unlock()
+	return &Handle{key: key, cache: g.cache, h: h}
+}
+
+// Destroy releases this Generation's references to every Handle it bound. A Handle becomes
+// eligible for eviction once no other Generation still references it.
+func (g *Generation) Destroy() {
+	g.mu.Lock()
+	keys := g.keys
+	g.keys = nil
+	g.destroyed = true
+	g.mu.Unlock()
+	for key := range keys {
+		g.cache.unref(key, g)
+	}
+}
@@ -0,0 +1,140 @@
+package machine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheBindDedupesConcurrentCallers(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	var calls int64
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (any, error) {
+		atomic.AddInt64(&calls, 1)
+		close(started)
+		<-release
+		return 42, nil
+	}
+
+	h1 := m.Cache().Bind("k", fn)
+	h2 := m.Cache().Bind("k", fn)
+
+	type result struct {
+		v   any
+		err error
+	}
+	results := make(chan result, 2)
+	go func() { v, err := h1.Get(context.Background()); results <- result{v, err} }()
+	go func() { v, err := h2.Get(context.Background()); results <- result{v, err} }()
+
+	<-started
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatalf("Get error: %v", r.err)
+		}
+		if r.v != 42 {
+			t.Fatalf("Get = %v, want 42", r.v)
+		}
+	}
+	if atomic.LoadInt64(&calls) != 1 {
+		t.Fatalf("fn called %d times, want exactly 1", atomic.LoadInt64(&calls))
+	}
+}
+
+func TestGenerationDestroyEvictsUnreferencedHandle(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	c := m.Cache()
+	gen := c.NewGeneration("g1")
+	gen.Bind("k", func(ctx context.Context) (any, error) { return "v1", nil })
+	gen.Destroy()
+
+	// After the only Generation referencing "k" is destroyed, a fresh Bind must recompute rather
+	// than resolve to a stale handle.
+	var recomputed int64
+	h := c.Bind("k", func(ctx context.Context) (any, error) {
+		atomic.AddInt64(&recomputed, 1)
+		return "v2", nil
+	})
+	v, err := h.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if v != "v2" || atomic.LoadInt64(&recomputed) != 1 {
+		t.Fatalf("Get = %v (recomputed=%d), want v2 recomputed once", v, atomic.LoadInt64(&recomputed))
+	}
+}
+
+// TestGenerationBindSurvivesConcurrentDestroyOfAnotherGeneration is a regression test: a
+// Generation.Bind racing a different Generation's Destroy on the same key must never leave the
+// Cache's lookup table pointing somewhere other than the handle the still-live Bind returned,
+// however the two interleave. (A handle count alone can't distinguish this from the legitimate
+// case where Destroy simply wins the race outright and a fresh Bind recomputes from scratch — so
+// this asserts directly on handle identity instead.)
+func TestGenerationBindSurvivesConcurrentDestroyOfAnotherGeneration(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+	c := m.Cache()
+
+	for i := 0; i < 2000; i++ {
+		key := i
+		fn := func(ctx context.Context) (any, error) { return i, nil }
+
+		gen1 := c.NewGeneration("g1")
+		gen1.Bind(key, fn)
+
+		gen2 := c.NewGeneration("g2")
+		var h2 *Handle
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h2 = gen2.Bind(key, fn)
+		}()
+		go func() {
+			defer wg.Done()
+			gen1.Destroy()
+		}()
+		wg.Wait()
+
+		// gen2's reference is still live (never destroyed); whatever handle it's bound to must
+		// still be the one a fresh Bind of the same key resolves to.
+		h3 := c.Bind(key, fn)
+		if h3.h != h2.h {
+			t.Fatalf("iteration %d: cache diverged from gen2's still-live handle after a concurrent Destroy of a different generation", i)
+		}
+		gen2.Destroy()
+	}
+}
+
+// TestCacheComputeOnCancelledMachineStillResolves is a regression test: Bind on a Cache owned by
+// an already-cancelled Machine must still resolve its Handle instead of wedging forever, since
+// Machine.Go silently drops work once its context has ended.
+func TestCacheComputeOnCancelledMachineStillResolves(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := New(ctx)
+	cancel()
+	defer m.Close()
+
+	h := m.Cache().Bind("k", func(ctx context.Context) (any, error) { return "v", nil })
+
+	getCtx, getCancel := context.WithTimeout(context.Background(), time.Second)
+	defer getCancel()
+	v, err := h.Get(getCtx)
+	if err != nil {
+		t.Fatalf("Get on a cancelled Machine's Cache wedged/errored: %v", err)
+	}
+	if v != "v" {
+		t.Fatalf("Get = %v, want v", v)
+	}
+}
@@ -0,0 +1,332 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ManifoldState describes the lifecycle state of an installed Manifold's worker.
+type ManifoldState string
+
+const (
+	ManifoldStarting ManifoldState = "starting"
+	ManifoldRunning  ManifoldState = "running"
+	ManifoldStopped  ManifoldState = "stopped"
+	ManifoldError    ManifoldState = "error"
+)
+
+// Worker is a unit of long-running work managed by the dependency engine. Kill asks the worker
+// to stop; Wait blocks until it has, returning the error (if any) that ended it.
+type Worker interface {
+	Kill()
+	Wait() error
+}
+
+// Manifold declares a named worker and the named inputs it depends on. Start is called to
+// (re)create the Worker whenever the manifold itself, or any of its inputs, bounces; getResource
+// resolves a named input to the value its Manifold's Output publishes. Output, if non-nil, lets
+// other manifolds depend on this one by exposing its Worker's resource.
+type Manifold struct {
+	Inputs []string
+	Start  func(ctx context.Context, getResource func(name string, out any) error) (Worker, error)
+	Output func(worker Worker, out any) error
+}
+
+// ManifoldReport is a snapshot of a single installed Manifold's state.
+type ManifoldReport struct {
+	Name     string
+	State    ManifoldState
+	LastErr  error
+	Restarts int
+	Inputs   []string
+}
+
+// Report is a snapshot of every Manifold installed on a Machine's engine.
+type Report struct {
+	Manifolds []ManifoldReport
+}
+
+type manifoldEntry struct {
+	name     string
+	mu       sync.Mutex
+	manifold Manifold
+	worker   Worker
+	state    ManifoldState
+	lastErr  error
+	restarts int
+	bounce   chan struct{}
+}
+
+// engine is the dependency-managed worker runtime installed on a Machine via Install.
+type engine struct {
+	machine    *Machine
+	mu         sync.RWMutex
+	entries    map[string]*manifoldEntry
+	isFatal    func(error) bool
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func newEngine(m *Machine, isFatal func(error) bool, minBackoff, maxBackoff time.Duration) *engine {
+	if isFatal == nil {
+		isFatal = func(error) bool { return false }
+	}
+	if minBackoff <= 0 {
+		minBackoff = 100 * time.Millisecond
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = 30 * time.Second
+	}
+	e := &engine{
+		machine:    m,
+		entries:    map[string]*manifoldEntry{},
+		isFatal:    isFatal,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+	}
+	go func() {
+		<-m.ctx.Done()
+		e.shutdown()
+	}()
+	return e
+}
+
+// install registers mf under name and (re)starts it, along with anything already installed that
+// depends on name.
+func (e *engine) install(name string, mf Manifold) {
+	e.mu.Lock()
+	entry, exists := e.entries[name]
+	if !exists {
+		entry = &manifoldEntry{name: name, manifold: mf, state: ManifoldStarting, bounce: make(chan struct{}, 1)}
+		e.entries[name] = entry
+	} else {
+		entry.mu.Lock()
+		entry.manifold = mf
+		entry.mu.Unlock()
+	}
+	e.mu.Unlock()
+
+	if !exists {
+		e.machine.Go(func(r Routine) error {
+			return e.run(r, entry)
+		})
+	} else {
+		entry.mu.Lock()
+		w := entry.worker
+		entry.mu.Unlock()
+		if w != nil {
+			w.Kill()
+		} else {
+			select {
+			case entry.bounce <- struct{}{}:
+			default:
+			}
+		}
+	}
+	e.bounceDependents(name)
+}
+
+// report returns a snapshot of every installed manifold.
+func (e *engine) report() *Report {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make([]ManifoldReport, 0, len(e.entries))
+	for _, entry := range e.entries {
+		entry.mu.Lock()
+		out = append(out, ManifoldReport{
+			Name:     entry.name,
+			State:    entry.state,
+			LastErr:  entry.lastErr,
+			Restarts: entry.restarts,
+			Inputs:   entry.manifold.Inputs,
+		})
+		entry.mu.Unlock()
+	}
+	return &Report{Manifolds: out}
+}
+
+// run starts entry's worker, waits for it to end, and restarts it with jittered exponential
+// backoff until the Machine's context is cancelled or a fatal error short-circuits everything.
+func (e *engine) run(r Routine, entry *manifoldEntry) error {
+	backoff := newExpBackoff(e.minBackoff, e.maxBackoff)
+	for {
+		if r.Context().Err() != nil {
+			return nil
+		}
+		entry.mu.Lock()
+		entry.state = ManifoldStarting
+		entry.mu.Unlock()
+
+		worker, err := entry.manifold.Start(r.Context(), e.getResource)
+		if err != nil {
+			entry.mu.Lock()
+			entry.state = ManifoldError
+			entry.lastErr = err
+			entry.restarts++
+			entry.mu.Unlock()
+			if e.isFatal(err) {
+				e.machine.Cancel()
+				return err
+			}
+			if !e.sleep(r.Context(), backoff.next(), entry.bounce) {
+				return nil
+			}
+			continue
+		}
+
+		entry.mu.Lock()
+		entry.worker = worker
+		entry.state = ManifoldRunning
+		entry.mu.Unlock()
+		backoff.reset()
+		e.bounceDependents(entry.name)
+
+		err = worker.Wait()
+		entry.mu.Lock()
+		entry.worker = nil
+		entry.mu.Unlock()
+		if r.Context().Err() != nil {
+			return nil
+		}
+		if err != nil && e.isFatal(err) {
+			entry.mu.Lock()
+			entry.state = ManifoldError
+			entry.lastErr = err
+			entry.mu.Unlock()
+			e.machine.Cancel()
+			return err
+		}
+
+		entry.mu.Lock()
+		entry.state = ManifoldStopped
+		entry.lastErr = err
+		entry.restarts++
+		entry.mu.Unlock()
+		e.bounceDependents(entry.name)
+		if !e.sleep(r.Context(), backoff.next(), entry.bounce) {
+			return nil
+		}
+	}
+}
+
+// sleep waits for d, or returns early if ctx ends (false: give up) or entry's bounce channel
+// fires (true: an input this manifold depends on just changed, so restart immediately instead of
+// finishing out the backoff).
+func (e *engine) sleep(ctx context.Context, d time.Duration, bounce chan struct{}) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-bounce:
+		return true
+	case <-time.After(d):
+		return true
+	}
+}
+
+// getResource resolves a named input's published resource via its Manifold's Output func.
+func (e *engine) getResource(name string, out any) error {
+	e.mu.RLock()
+	entry, ok := e.entries[name]
+	e.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("machine: manifold %q is not installed", name)
+	}
+	entry.mu.Lock()
+	worker, state, mf := entry.worker, entry.state, entry.manifold
+	entry.mu.Unlock()
+	if state != ManifoldRunning || worker == nil {
+		return fmt.Errorf("machine: manifold %q is not running", name)
+	}
+	if mf.Output == nil {
+		return fmt.Errorf("machine: manifold %q does not expose an output", name)
+	}
+	return mf.Output(worker, out)
+}
+
+// bounceDependents stops every installed manifold whose Inputs include name, so run's restart
+// loop picks up the changed dependency: a running worker is killed, and a dependent currently
+// waiting out its backoff (worker == nil, between a failed Start or a stopped worker) is woken to
+// retry immediately instead of waiting for its backoff to elapse on its own.
+func (e *engine) bounceDependents(name string) {
+	e.mu.RLock()
+	var deps []*manifoldEntry
+	for _, entry := range e.entries {
+		for _, in := range entry.manifold.Inputs {
+			if in == name {
+				deps = append(deps, entry)
+				break
+			}
+		}
+	}
+	e.mu.RUnlock()
+	for _, d := range deps {
+		d.mu.Lock()
+		w := d.worker
+		d.mu.Unlock()
+		if w != nil {
+			w.Kill()
+			continue
+		}
+		select {
+		case d.bounce <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// shutdown kills every installed worker in reverse dependency order: a manifold is killed only
+// once nothing still depending on it remains running.
+func (e *engine) shutdown() {
+	e.mu.RLock()
+	entries := make([]*manifoldEntry, 0, len(e.entries))
+	for _, entry := range e.entries {
+		entries = append(entries, entry)
+	}
+	e.mu.RUnlock()
+
+	remaining := entries
+	dependents := func(name string) int {
+		n := 0
+		for _, entry := range remaining {
+			for _, in := range entry.manifold.Inputs {
+				if in == name {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	kill := func(entry *manifoldEntry) {
+		entry.mu.Lock()
+		w := entry.worker
+		entry.mu.Unlock()
+		if w != nil {
+			w.Kill()
+			w.Wait()
+		}
+	}
+
+	for len(remaining) > 0 {
+		var next []*manifoldEntry
+		killedAny := false
+		for _, entry := range remaining {
+			if dependents(entry.name) == 0 {
+				kill(entry)
+				killedAny = true
+			} else {
+				next = append(next, entry)
+			}
+		}
+		if !killedAny {
+			// dependency cycle: nothing left with zero dependents, kill what remains.
+			for _, entry := range next {
+				kill(entry)
+			}
+			return
+		}
+		remaining = next
+	}
+}
@@ -0,0 +1,108 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeWorker struct {
+	kill chan struct{}
+	err  error
+}
+
+func newFakeWorker(err error) *fakeWorker {
+	return &fakeWorker{kill: make(chan struct{}), err: err}
+}
+
+func (w *fakeWorker) Kill() {
+	select {
+	case <-w.kill:
+	default:
+		close(w.kill)
+	}
+}
+
+func (w *fakeWorker) Wait() error {
+	<-w.kill
+	return w.err
+}
+
+func TestEngineInstallAndReport(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	started := make(chan struct{}, 1)
+	m.Install("a", Manifold{
+		Start: func(ctx context.Context, _ func(string, any) error) (Worker, error) {
+			started <- struct{}{}
+			w := newFakeWorker(nil)
+			go func() {
+				<-ctx.Done()
+				w.Kill()
+			}()
+			return w, nil
+		},
+	})
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("manifold never started")
+	}
+
+	report := m.Report()
+	if len(report.Manifolds) != 1 || report.Manifolds[0].Name != "a" {
+		t.Fatalf("Report = %+v, want one manifold named a", report.Manifolds)
+	}
+}
+
+// TestEngineBounceWakesBackoffSleepingDependent is a regression test: a dependent whose Start
+// keeps failing (so it has no running worker, only a pending backoff sleep) must be woken and
+// retried promptly when the input it's waiting on bounces, not left to its own backoff timer.
+func TestEngineBounceWakesBackoffSleepingDependent(t *testing.T) {
+	m := New(context.Background(), WithBackoff(time.Hour, time.Hour))
+	defer m.Close()
+
+	var attempts int64
+	ready := make(chan struct{})
+	m.Install("dep", Manifold{
+		Inputs: []string{"input"},
+		Start: func(ctx context.Context, getResource func(string, any) error) (Worker, error) {
+			n := atomic.AddInt64(&attempts, 1)
+			if n == 1 {
+				return nil, errors.New("input not ready yet")
+			}
+			close(ready)
+			w := newFakeWorker(nil)
+			go func() {
+				<-ctx.Done()
+				w.Kill()
+			}()
+			return w, nil
+		},
+	})
+
+	for atomic.LoadInt64(&attempts) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	m.Install("input", Manifold{
+		Start: func(ctx context.Context, _ func(string, any) error) (Worker, error) {
+			w := newFakeWorker(nil)
+			go func() {
+				<-ctx.Done()
+				w.Kill()
+			}()
+			return w, nil
+		},
+	})
+
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+		t.Fatal("dependent stuck in its hour-long backoff instead of being woken by the bounce")
+	}
+}
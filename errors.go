@@ -0,0 +1,16 @@
+package machine
+
+// Cancel wraps an error returned from a Func to signal that it should cancel every routine owned
+// by the Machine. Unlike other errors, a Cancel is never returned by Wait or WaitCtx.
+type Cancel struct {
+	Err error
+}
+
+func (c Cancel) Error() string {
+	if c.Err == nil {
+		return "machine: cancel"
+	}
+	return c.Err.Error()
+}
+
+func (c Cancel) Unwrap() error { return c.Err }
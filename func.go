@@ -0,0 +1,7 @@
+package machine
+
+// Func is the work performed by a managed goroutine. It receives the Routine handle for the
+// goroutine it is running in. A non-nil error is returned by Wait and, for a routine restarted
+// via WithRestart, shown in Stats as the next attempt's LastError; wrap it in Cancel to stop every
+// routine owned by the Machine.
+type Func func(routine Routine) error
@@ -4,8 +4,10 @@ package machine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,21 +15,32 @@ import (
 
 // Machine is a zero dependency runtime for managed goroutines. It is inspired by errgroup.Group with extra bells & whistles:
 type Machine struct {
-	parent    *Machine
-	children  []*Machine
-	childMu   sync.RWMutex
-	cache     Cache
-	done      chan struct{}
-	cancel    func()
-	ctx       context.Context
-	workQueue chan *work
-	mu        sync.RWMutex
-	routines  map[int]Routine
-	max       int
-	closeOnce sync.Once
-	doneOnce  sync.Once
-	pubsub    PubSub
-	total     int64
+	parent       *Machine
+	children     []*Machine
+	childMu      sync.RWMutex
+	cache        Cache
+	done         chan struct{}
+	cancel       func()
+	ctx          context.Context
+	workQueue    chan *work
+	mu           sync.RWMutex
+	routines     map[int]Routine
+	max          int
+	closeOnce    sync.Once
+	doneOnce     sync.Once
+	pubsub       PubSub
+	total        int64
+	scheduler    *scheduler
+	engine       *engine
+	panicHandler func(pid int, v any, stack []byte)
+	sem          chan struct{}
+	wg           sync.WaitGroup
+	condMu       sync.Mutex
+	cond         *sync.Cond
+	errMu        sync.Mutex
+	firstErr     error
+	onStart      func(RoutineStats)
+	onFinish     func(RoutineStats, error)
 }
 
 // New Creates a new machine instance with the given root context & options
@@ -64,11 +77,34 @@ func New(ctx context.Context, options ...Opt) *Machine {
 		doneOnce:  sync.Once{},
 		pubsub:    opts.pubsub,
 		total:     0,
+		sem:       make(chan struct{}, opts.maxRoutines),
 	}
+	m.cond = sync.NewCond(&m.condMu)
+	m.panicHandler = opts.panicHandler
+	m.onStart = opts.onStart
+	m.onFinish = opts.onFinish
+	if c, ok := m.cache.(*cache); ok {
+		c.machine = m
+	}
+	m.scheduler = newScheduler(m)
+	m.engine = newEngine(m, opts.isFatal, opts.minBackoff, opts.maxBackoff)
 	go m.serve()
+	go m.scheduler.run()
 	return m
 }
 
+// Install registers a named Manifold with the Machine's dependency engine and (re)starts it,
+// along with anything already installed that depends on name. Workers run through Go, so
+// cancellation, timeouts, tags and Stats all continue to work for them.
+func (m *Machine) Install(name string, mf Manifold) {
+	m.engine.install(name, mf)
+}
+
+// Report returns a snapshot of every Manifold installed on the Machine's dependency engine.
+func (m *Machine) Report() *Report {
+	return m.engine.report()
+}
+
 // Cache returns the machines Cache implementation
 func (m *Machine) Cache() Cache {
 	return m.cache
@@ -90,7 +126,8 @@ func (p *Machine) Total() int {
 	return int(atomic.LoadInt64(&p.total))
 }
 
-// Go calls the given function in a new goroutine.
+// Go calls the given function in a new goroutine. It blocks once the Machine is at capacity
+// (max concurrently running routines) until a slot frees up or the Machine's context ends.
 //
 // The first call to return a non-nil error who's cause is machine.Cancel cancels the context of every job.
 // All errors that are not of type machine.Cancel will be returned by Wait.
@@ -99,11 +136,30 @@ func (m *Machine) Go(fn Func, opts ...GoOpt) {
 	for _, opt := range opts {
 		opt(o)
 	}
-	if m.ctx.Err() == nil {
-		m.workQueue <- &work{
-			opts: o,
-			fn:   fn,
-		}
+	if m.ctx.Err() != nil {
+		return
+	}
+	if o.schedule != nil {
+		m.scheduler.register(fn, o)
+		return
+	}
+	m.dispatch(&work{opts: o, fn: fn})
+}
+
+// dispatch acquires an admission slot (blocking if the Machine is at capacity) and hands w to
+// serve, or gives up if the Machine's context ends first.
+func (m *Machine) dispatch(w *work) {
+	select {
+	case m.sem <- struct{}{}:
+	case <-m.ctx.Done():
+		return
+	}
+	m.wg.Add(1)
+	select {
+	case m.workQueue <- w:
+	case <-m.ctx.Done():
+		<-m.sem
+		m.wg.Done()
 	}
 }
 
@@ -113,13 +169,11 @@ func (m *Machine) serve() {
 		case <-m.done:
 			return
 		case w := <-m.workQueue:
+			originalFn := w.fn
 			if len(w.opts.middlewares) > 0 {
 				for _, ware := range w.opts.middlewares {
 					w.fn = ware(w.fn)
 				}
-			}
-			for x := m.Current(); x >= m.max; x = m.Current() {
-
 			}
 			if w.opts.id == 0 {
 				w.opts.id = rand.Int()
@@ -141,31 +195,162 @@ func (m *Machine) serve() {
 				start:    time.Now(),
 				doneOnce: sync.Once{},
 				cancel:   cancel,
+				restarts: w.opts.restarts,
 			}
+			routine.lastErr, routine.lastPanic = w.opts.lastErr, w.opts.lastPanic
 			m.mu.Lock()
 			m.routines[w.opts.id] = routine
 			m.mu.Unlock()
 			atomic.AddInt64(&m.total, 1)
-			go func() {
-				defer func() {
-					r := recover()
-					if _, ok := r.(error); ok {
-						fmt.Println("machine: panic recovered")
-					}
-				}()
-				defer routine.done()
-				w.fn(routine)
-			}()
+			if m.onStart != nil {
+				m.onStart(routineStats(routine))
+			}
+			go m.run(w, originalFn, routine)
+		}
+	}
+}
+
+// run executes w.fn, recovering panics (captured via runtime/debug.Stack) so one failing
+// routine never takes down the Machine. The routine's own stats (Restarts, LastError, LastPanic)
+// are updated and, if w.opts.restart is set and its policy and RestartPolicy.MaxRetries allow it,
+// the original (pre-middleware) Func is re-scheduled through workQueue with the same PID and tags
+// after an exponential backoff (jittered when RestartPolicy.Jitter is set).
+func (m *Machine) run(w *work, originalFn Func, routine *goRoutine) {
+	defer routine.done()
+
+	var (
+		panicked bool
+		panicVal any
+		stack    []byte
+	)
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				panicked = true
+				panicVal = r
+				stack = debug.Stack()
+			}
+		}()
+		return w.fn(routine)
+	}()
+
+	if panicked {
+		if m.panicHandler != nil {
+			m.panicHandler(routine.id, panicVal, stack)
+		} else if !w.opts.recover {
+			fmt.Println("machine: panic recovered:", panicVal)
+		}
+		err = fmt.Errorf("machine: panic recovered: %v", panicVal)
+		w.opts.lastPanic = stack
+	}
+	if err != nil {
+		w.opts.lastErr = err
+	}
+	routine.setRestartInfo(w.opts.restarts, w.opts.lastErr, w.opts.lastPanic)
+	if m.onFinish != nil {
+		m.onFinish(routineStats(routine), err)
+	}
+	m.recordResult(err)
+
+	if w.opts.restart == nil || m.ctx.Err() != nil {
+		return
+	}
+	policy := *w.opts.restart
+	if !policy.shouldRestart(err) || !policy.allows(w.opts.restarts) {
+		return
+	}
+	delay := policy.delay(w.opts.restarts)
+	w.opts.restarts++
+	go func() {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(delay):
 		}
+		if m.ctx.Err() == nil {
+			m.dispatch(&work{opts: w.opts, fn: originalFn})
+		}
+	}()
+}
+
+// recordResult keeps the first non-Cancel error seen across every routine the Machine has run,
+// surfaced later by Wait/WaitCtx. A Cancel error instead cancels the whole Machine.
+func (m *Machine) recordResult(err error) {
+	if err == nil {
+		return
 	}
+	var c Cancel
+	if errors.As(err, &c) {
+		m.Cancel()
+		return
+	}
+	m.errMu.Lock()
+	if m.firstErr == nil {
+		m.firstErr = err
+	}
+	m.errMu.Unlock()
 }
 
-// Wait blocks until total active goroutine count reaches zero for the instance and all of it's children.
-func (m *Machine) Wait() {
+// Wait blocks until total active goroutine count reaches zero for the instance and all of its
+// children, then returns the first non-Cancel error returned by any of their Funcs, if any.
+func (m *Machine) Wait() error {
+	m.condMu.Lock()
 	for m.Current() > 0 {
-		for len(m.workQueue) > 0 {
+		m.cond.Wait()
+	}
+	m.condMu.Unlock()
+	return m.firstErrOf()
+}
+
+// WaitCtx is like Wait, but also returns early with ctx.Err() if ctx is cancelled before every
+// routine finishes.
+func (m *Machine) WaitCtx(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.waitGroups()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return m.firstErrOf()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Machine) waitGroups() {
+	m.wg.Wait()
+	for _, child := range m.children {
+		child.waitGroups()
+	}
+}
+
+func (m *Machine) firstErrOf() error {
+	m.errMu.Lock()
+	err := m.firstErr
+	m.errMu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, child := range m.children {
+		if err := child.firstErrOf(); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// release frees the admission slot and goroutine-count bookkeeping held by a finished routine,
+// then wakes any Wait callers on this Machine and every ancestor up to the root, since a parent's
+// Current (and so its Wait) recurses into every descendant.
+func (m *Machine) release() {
+	<-m.sem
+	m.wg.Done()
+	for p := m; p != nil; p = p.parent {
+		p.condMu.Lock()
+		p.cond.Broadcast()
+		p.condMu.Unlock()
+	}
 }
 
 // Cancel cancels every goroutines context
@@ -180,6 +365,19 @@ func (p *Machine) Cancel() {
 	})
 }
 
+// routineStats builds the RoutineStats snapshot for a single Routine.
+func routineStats(v Routine) RoutineStats {
+	return RoutineStats{
+		PID:       v.PID(),
+		Start:     v.Start(),
+		Duration:  v.Duration(),
+		Tags:      v.Tags(),
+		Restarts:  v.Restarts(),
+		LastError: v.LastError(),
+		LastPanic: v.LastPanic(),
+	}
+}
+
 // Stats returns Goroutine information from the machine
 func (m *Machine) Stats() *Stats {
 	m.mu.RLock()
@@ -187,12 +385,7 @@ func (m *Machine) Stats() *Stats {
 	copied := []RoutineStats{}
 	for _, v := range m.routines {
 		if v != nil {
-			copied = append(copied, RoutineStats{
-				PID:      v.PID(),
-				Start:    v.Start(),
-				Duration: v.Duration(),
-				Tags:     v.Tags(),
-			})
+			copied = append(copied, routineStats(v))
 		}
 	}
 	return &Stats{
@@ -200,6 +393,7 @@ func (m *Machine) Stats() *Stats {
 		Routines:      copied,
 		TotalChildren: len(m.children),
 		HasParent:     m.parent != nil,
+		Schedules:     m.scheduler.snapshot(),
 	}
 }
 
@@ -0,0 +1,99 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestWaitAcrossNestedSubRegression is a regression test: Wait on a Machine more than one Sub()
+// level above where a routine actually runs must still return once that routine finishes,
+// because release broadcasts all the way up the ancestor chain, not just to the immediate parent.
+func TestWaitAcrossNestedSubRegression(t *testing.T) {
+	root := New(context.Background())
+	defer root.Close()
+	child := root.Sub()
+	grandchild := child.Sub()
+
+	done := make(chan struct{})
+	grandchild.Go(func(Routine) error {
+		close(done)
+		return nil
+	})
+
+	waited := make(chan error, 1)
+	go func() { waited <- root.Wait() }()
+
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Fatalf("Wait() = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("root.Wait() never returned for a routine running two Sub() levels down")
+	}
+	<-done
+}
+
+func TestWaitReturnsFirstNonCancelError(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	boom := errors.New("boom")
+	m.Go(func(Routine) error { return boom })
+
+	if err := m.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("Wait() = %v, want %v", err, boom)
+	}
+}
+
+func TestWaitCtxReturnsEarlyOnParentCancellation(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	block := make(chan struct{})
+	m.Go(func(Routine) error {
+		<-block
+		return nil
+	})
+	defer close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := m.WaitCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitCtx() = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestGoBlocksAtCapacityUntilSlotFrees(t *testing.T) {
+	m := New(context.Background(), WithMaxRoutines(1))
+	defer m.Close()
+
+	block := make(chan struct{})
+	m.Go(func(Routine) error {
+		<-block
+		return nil
+	})
+
+	admitted := make(chan struct{})
+	go func() {
+		m.Go(func(Routine) error { return nil })
+		close(admitted)
+	}()
+
+	select {
+	case <-admitted:
+		t.Fatal("second Go was admitted before the Machine had a free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(block)
+	select {
+	case <-admitted:
+	case <-time.After(time.Second):
+		t.Fatal("second Go never admitted after a slot freed up")
+	}
+}
@@ -0,0 +1,136 @@
+package machine
+
+import "time"
+
+// Opt configures a Machine at construction time.
+type Opt func(*option)
+
+type option struct {
+	maxRoutines  int
+	cache        Cache
+	pubsub       PubSub
+	parent       *Machine
+	children     []*Machine
+	isFatal      func(error) bool
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	panicHandler func(pid int, v any, stack []byte)
+	onStart      func(RoutineStats)
+	onFinish     func(RoutineStats, error)
+}
+
+// WithMaxRoutines caps the number of goroutines the Machine will run concurrently.
+func WithMaxRoutines(max int) Opt {
+	return func(o *option) {
+		o.maxRoutines = max
+	}
+}
+
+// WithCache sets a custom Cache implementation for the Machine.
+func WithCache(c Cache) Opt {
+	return func(o *option) {
+		o.cache = c
+	}
+}
+
+// WithPubSub sets a custom PubSub implementation for the Machine.
+func WithPubSub(p PubSub) Opt {
+	return func(o *option) {
+		o.pubsub = p
+	}
+}
+
+// WithParent associates the given Machine as the parent of the one being constructed.
+func WithParent(m *Machine) Opt {
+	return func(o *option) {
+		o.parent = m
+	}
+}
+
+// WithIsFatal classifies errors returned by installed Manifold workers. When fn returns true the
+// whole Machine is cancelled instead of the failing manifold being restarted.
+func WithIsFatal(fn func(error) bool) Opt {
+	return func(o *option) {
+		o.isFatal = fn
+	}
+}
+
+// WithBackoff bounds the exponential backoff applied between manifold restarts; jitter then scales
+// each delay to between half and all of its bounded value.
+func WithBackoff(min, max time.Duration) Opt {
+	return func(o *option) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithPanicHandler registers a callback invoked with the PID, recovered value and captured stack
+// whenever a managed routine panics, instead of the Machine printing it to stdout.
+func WithPanicHandler(fn func(pid int, v any, stack []byte)) Opt {
+	return func(o *option) {
+		o.panicHandler = fn
+	}
+}
+
+// WithOnPanic is an alias for WithPanicHandler, named to match WithOnStart and WithOnFinish.
+func WithOnPanic(fn func(pid int, v any, stack []byte)) Opt {
+	return WithPanicHandler(fn)
+}
+
+// WithOnStart registers a callback invoked with a routine's Stats as soon as it starts running.
+func WithOnStart(fn func(RoutineStats)) Opt {
+	return func(o *option) {
+		o.onStart = fn
+	}
+}
+
+// WithOnFinish registers a callback invoked with a routine's Stats and the error (if any) its
+// Func returned, once it finishes.
+func WithOnFinish(fn func(RoutineStats, error)) Opt {
+	return func(o *option) {
+		o.onFinish = fn
+	}
+}
+
+// Middleware wraps a Func so cross-cutting behaviour (logging, recovery, retries) can be layered
+// around a managed goroutine without changing its body.
+type Middleware func(Func) Func
+
+// GoOpt configures a single call to Machine.Go.
+type GoOpt func(*goOpts)
+
+type goOpts struct {
+	id          int
+	tags        []string
+	timeout     *time.Duration
+	middlewares []Middleware
+	schedule    *scheduleOpts
+	reusePID    bool
+	restart     *RestartPolicy
+	restarts    int
+	lastErr     error
+	lastPanic   []byte
+	recover     bool
+}
+
+// WithTags attaches tags to a routine; they are surfaced via Stats and can be used by
+// middlewares and observers to identify related work.
+func WithTags(tags ...string) GoOpt {
+	return func(o *goOpts) {
+		o.tags = tags
+	}
+}
+
+// WithTimeout bounds the routine's context with the given duration.
+func WithTimeout(d time.Duration) GoOpt {
+	return func(o *goOpts) {
+		o.timeout = &d
+	}
+}
+
+// WithMiddleware wraps the routine's Func with the given middlewares, applied in the order given.
+func WithMiddleware(mw ...Middleware) GoOpt {
+	return func(o *goOpts) {
+		o.middlewares = append(o.middlewares, mw...)
+	}
+}
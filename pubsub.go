@@ -0,0 +1,74 @@
+package machine
+
+import "sync"
+
+// PubSub is a concurrency-safe publish/subscribe broker scoped to a Machine, closed alongside it.
+type PubSub interface {
+	Subscribe(topic string) (id int, ch chan interface{})
+	Unsubscribe(topic string, id int)
+	Publish(topic string, value interface{})
+	Close()
+}
+
+type pubSub struct {
+	subscriptions map[string]map[int]chan interface{}
+	subMu         sync.RWMutex
+	nextID        int
+}
+
+// Subscribe returns a channel that receives every value published to topic from now on, along
+// with the id needed to Unsubscribe it later.
+func (p *pubSub) Subscribe(topic string) (int, chan interface{}) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	if p.subscriptions == nil {
+		p.subscriptions = map[string]map[int]chan interface{}{}
+	}
+	if p.subscriptions[topic] == nil {
+		p.subscriptions[topic] = map[int]chan interface{}{}
+	}
+	p.nextID++
+	id := p.nextID
+	ch := make(chan interface{}, 1)
+	p.subscriptions[topic][id] = ch
+	return id, ch
+}
+
+// Unsubscribe removes and closes the subscription id returned by Subscribe for topic.
+func (p *pubSub) Unsubscribe(topic string, id int) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	subs, ok := p.subscriptions[topic]
+	if !ok {
+		return
+	}
+	if ch, ok := subs[id]; ok {
+		delete(subs, id)
+		close(ch)
+	}
+}
+
+// Publish sends value to every current subscriber of topic. Slow subscribers whose channel is
+// already full are skipped rather than blocking the publisher.
+func (p *pubSub) Publish(topic string, value interface{}) {
+	p.subMu.RLock()
+	defer p.subMu.RUnlock()
+	for _, ch := range p.subscriptions[topic] {
+		select {
+		case ch <- value:
+		default:
+		}
+	}
+}
+
+// Close unsubscribes and closes every outstanding subscription.
+func (p *pubSub) Close() {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	for _, subs := range p.subscriptions {
+		for _, ch := range subs {
+			close(ch)
+		}
+	}
+	p.subscriptions = map[string]map[int]chan interface{}{}
+}
@@ -0,0 +1,96 @@
+package machine
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RestartPolicyKind selects when a routine should be restarted after it finishes.
+type RestartPolicyKind int
+
+const (
+	// Never means the routine is not restarted.
+	Never RestartPolicyKind = iota
+	// OnFailure restarts the routine only if it panicked or returned a non-nil error.
+	OnFailure
+	// Always restarts the routine regardless of how it finished.
+	Always
+)
+
+// RestartPolicy configures whether, and with what backoff, a routine is restarted after it
+// panics or returns.
+type RestartPolicy struct {
+	Kind            RestartPolicyKind
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// MaxRetries caps the number of restarts; 0 means unlimited.
+	MaxRetries int
+	Jitter     bool
+}
+
+// WithRestart causes the routine's Func to be re-scheduled, with the same PID and tags, whenever
+// it panics or returns, according to policy. Panics are always recovered when WithRestart is
+// used, even without WithRecover.
+func WithRestart(policy RestartPolicy) GoOpt {
+	return func(o *goOpts) {
+		o.restart = &policy
+	}
+}
+
+// WithRecover silences the Machine's default "machine: panic recovered" log for this routine.
+// Panics are always recovered regardless of this option; use WithRecover when a WithPanicHandler
+// (or the routine's own Stats) is sufficient and the default log line would be noise.
+func WithRecover() GoOpt {
+	return func(o *goOpts) {
+		o.recover = true
+	}
+}
+
+// shouldRestart reports whether the policy restarts a routine that finished with err (nil on a
+// clean return, non-nil on a returned error or a recovered panic).
+func (p RestartPolicy) shouldRestart(err error) bool {
+	switch p.Kind {
+	case Always:
+		return true
+	case OnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// allows reports whether attempt (the 0-based count of restarts already performed) is still
+// permitted by MaxRetries.
+func (p RestartPolicy) allows(attempt int) bool {
+	return p.MaxRetries <= 0 || attempt < p.MaxRetries
+}
+
+// delay computes the backoff to wait before performing the attempt-th (0-based) restart.
+func (p RestartPolicy) delay(attempt int) time.Duration {
+	initial := p.InitialInterval
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	max := p.MaxInterval
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	mult := p.Multiplier
+	if mult <= 1 {
+		mult = 2
+	}
+
+	d := initial
+	for i := 0; i < attempt; i++ {
+		d = time.Duration(float64(d) * mult)
+		if d >= max {
+			d = max
+			break
+		}
+	}
+	if p.Jitter {
+		d = time.Duration(float64(d) * (0.5 + rand.Float64()*0.5))
+	}
+	return d
+}
@@ -0,0 +1,74 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRestartPolicyShouldRestart(t *testing.T) {
+	cases := []struct {
+		kind  RestartPolicyKind
+		err   error
+		want  bool
+		label string
+	}{
+		{Never, errors.New("boom"), false, "never+err"},
+		{OnFailure, nil, false, "onFailure+nil"},
+		{OnFailure, errors.New("boom"), true, "onFailure+err"},
+		{Always, nil, true, "always+nil"},
+	}
+	for _, c := range cases {
+		p := RestartPolicy{Kind: c.kind}
+		if got := p.shouldRestart(c.err); got != c.want {
+			t.Errorf("%s: shouldRestart = %v, want %v", c.label, got, c.want)
+		}
+	}
+}
+
+func TestRestartPolicyAllowsMaxRetries(t *testing.T) {
+	p := RestartPolicy{MaxRetries: 2}
+	if !p.allows(0) || !p.allows(1) {
+		t.Fatal("expected attempts under MaxRetries to be allowed")
+	}
+	if p.allows(2) {
+		t.Fatal("expected attempt at MaxRetries to be disallowed")
+	}
+}
+
+func TestRestartPolicyDelayGrowsAndCaps(t *testing.T) {
+	p := RestartPolicy{InitialInterval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond, Multiplier: 2}
+	if d := p.delay(0); d != 10*time.Millisecond {
+		t.Fatalf("delay(0) = %v, want 10ms", d)
+	}
+	if d := p.delay(1); d != 20*time.Millisecond {
+		t.Fatalf("delay(1) = %v, want 20ms", d)
+	}
+	if d := p.delay(10); d != 50*time.Millisecond {
+		t.Fatalf("delay(10) = %v, want capped at 50ms", d)
+	}
+}
+
+func TestMachineRecoversPanicAndRestarts(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	var attempts int64
+	m.Go(func(r Routine) error {
+		if atomic.AddInt64(&attempts, 1) == 1 {
+			panic("boom")
+		}
+		return nil
+	}, WithRestart(RestartPolicy{Kind: Always, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}))
+
+	deadline := time.After(time.Second)
+	for atomic.LoadInt64(&attempts) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("only %d attempts, want at least 2 (panic recovered, then restarted)", atomic.LoadInt64(&attempts))
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
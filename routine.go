@@ -0,0 +1,136 @@
+package machine
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Routine is the handle a Func receives for the managed goroutine it is running in.
+type Routine interface {
+	PID() int
+	Tags() []string
+	Start() time.Time
+	Duration() time.Duration
+	Context() context.Context
+	Restarts() int
+	LastError() error
+	LastPanic() []byte
+
+	// AfterFunc registers f to run once this routine's context is cancelled or the routine
+	// completes, whichever happens first, without the caller having to spawn a goroutine to
+	// watch Context().Done() itself. Callbacks registered on the same Routine run synchronously,
+	// in LIFO order.
+	AfterFunc(f func())
+}
+
+type goRoutine struct {
+	machine  *Machine
+	ctx      context.Context
+	id       int
+	tags     []string
+	start    time.Time
+	doneOnce sync.Once
+	cancel   func()
+
+	restartMu sync.RWMutex
+	restarts  int
+	lastErr   error
+	lastPanic []byte
+
+	afterMu    sync.Mutex
+	afterFired bool
+	afterFuncs []func()
+	watchOnce  sync.Once
+}
+
+func (r *goRoutine) PID() int                { return r.id }
+func (r *goRoutine) Tags() []string          { return r.tags }
+func (r *goRoutine) Start() time.Time        { return r.start }
+func (r *goRoutine) Duration() time.Duration { return time.Since(r.start) }
+func (r *goRoutine) Context() context.Context {
+	return r.ctx
+}
+
+func (r *goRoutine) Restarts() int {
+	r.restartMu.RLock()
+	defer r.restartMu.RUnlock()
+	return r.restarts
+}
+
+func (r *goRoutine) LastError() error {
+	r.restartMu.RLock()
+	defer r.restartMu.RUnlock()
+	return r.lastErr
+}
+
+func (r *goRoutine) LastPanic() []byte {
+	r.restartMu.RLock()
+	defer r.restartMu.RUnlock()
+	return r.lastPanic
+}
+
+// setRestartInfo records this attempt's restart count, error and panic stack for Stats.
+func (r *goRoutine) setRestartInfo(restarts int, lastErr error, lastPanic []byte) {
+	r.restartMu.Lock()
+	defer r.restartMu.Unlock()
+	r.restarts = restarts
+	r.lastErr = lastErr
+	r.lastPanic = lastPanic
+}
+
+// AfterFunc registers f to run once this routine's context is cancelled or the routine
+// completes. If that has already happened, f runs immediately in its own goroutine. The first
+// call to AfterFunc on a Routine lazily starts the single goroutine that watches this Routine's
+// own Context().Done() — most routines never call AfterFunc, so most never pay for one.
+func (r *goRoutine) AfterFunc(f func()) {
+	r.afterMu.Lock()
+	if r.afterFired {
+		r.afterMu.Unlock()
+		go f()
+		return
+	}
+	r.afterFuncs = append(r.afterFuncs, f)
+	r.afterMu.Unlock()
+	r.watchOnce.Do(func() { go r.watch() })
+}
+
+// fireAfterFuncs runs every pending AfterFunc callback synchronously, most-recently-registered
+// first. It is safe to call more than once; only the first call does anything.
+func (r *goRoutine) fireAfterFuncs() {
+	r.afterMu.Lock()
+	if r.afterFired {
+		r.afterMu.Unlock()
+		return
+	}
+	r.afterFired = true
+	fns := r.afterFuncs
+	r.afterFuncs = nil
+	r.afterMu.Unlock()
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
+
+// watch fires r's pending AfterFunc callbacks as soon as its own context is cancelled, even if
+// its Func keeps running past that point — mirroring context.AfterFunc's semantics for a
+// WithTimeout (or otherwise externally cancelled) Routine. Only started lazily, by AfterFunc
+// itself, so a Routine that never registers a callback never pays for this goroutine; fireAfterFuncs'
+// idempotency means it's harmless if done() already fired the callbacks first.
+func (r *goRoutine) watch() {
+	<-r.ctx.Done()
+	r.fireAfterFuncs()
+}
+
+// done cancels the routine's context, removes it from its Machine's routine table, releases the
+// Machine's admission slot for it, and fires any pending AfterFunc callbacks.
+func (r *goRoutine) done() {
+	r.doneOnce.Do(func() {
+		r.cancel()
+		r.machine.mu.Lock()
+		delete(r.machine.routines, r.id)
+		r.machine.mu.Unlock()
+		r.fireAfterFuncs()
+		r.machine.release()
+	})
+}
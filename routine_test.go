@@ -0,0 +1,149 @@
+package machine
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestAfterFuncFiresOnRoutineTimeoutEvenIfFuncIgnoresIt is a regression test: a routine using
+// WithTimeout whose Func doesn't observe ctx.Done() must still have its AfterFunc callbacks fire
+// promptly once its own context expires, not only when the whole Machine is cancelled or the Func
+// eventually returns.
+func TestAfterFuncFiresOnRoutineTimeoutEvenIfFuncIgnoresIt(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	fired := make(chan struct{})
+	ignoreCtx := make(chan struct{})
+	defer close(ignoreCtx)
+
+	m.Go(func(r Routine) error {
+		r.AfterFunc(func() { close(fired) })
+		<-ignoreCtx
+		return nil
+	}, WithTimeout(50*time.Millisecond))
+
+	select {
+	case <-fired:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("AfterFunc never fired after the routine's own WithTimeout expired")
+	}
+}
+
+func TestAfterFuncFiresInLIFOOrder(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	var mu sync.Mutex
+	var order []int
+	done := make(chan struct{})
+
+	m.Go(func(r Routine) error {
+		for i := 0; i < 3; i++ {
+			i := i
+			r.AfterFunc(func() {
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				if i == 0 {
+					close(done)
+				}
+			})
+		}
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("AfterFunc callbacks never ran")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []int{2, 1, 0}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestOnStartAndOnFinishHooksFire(t *testing.T) {
+	var startPID, finishPID int
+	var finishErr error
+	started := make(chan struct{})
+	finished := make(chan struct{})
+
+	m := New(context.Background(),
+		WithOnStart(func(s RoutineStats) {
+			startPID = s.PID
+			close(started)
+		}),
+		WithOnFinish(func(s RoutineStats, err error) {
+			finishPID = s.PID
+			finishErr = err
+			close(finished)
+		}),
+	)
+	defer m.Close()
+
+	m.Go(func(Routine) error { return nil }, WithTags("t"))
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("WithOnStart hook never fired")
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("WithOnFinish hook never fired")
+	}
+	if startPID == 0 || startPID != finishPID {
+		t.Fatalf("startPID = %d, finishPID = %d, want matching non-zero PIDs", startPID, finishPID)
+	}
+	if finishErr != nil {
+		t.Fatalf("finishErr = %v, want nil", finishErr)
+	}
+}
+
+// TestAfterFuncWatcherOnlySpawnedWhenUsed is a regression test: a routine that never calls
+// AfterFunc must not have a dedicated watcher goroutine running for it, keeping goroutine cost
+// proportional to AfterFunc usage rather than O(N) over every dispatched routine.
+func TestAfterFuncWatcherOnlySpawnedWhenUsed(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	before := runtime.NumGoroutine()
+
+	block := make(chan struct{})
+	const n = 50
+	for i := 0; i < n; i++ {
+		m.Go(func(Routine) error {
+			<-block
+			return nil
+		})
+	}
+	for m.Current() < n {
+		time.Sleep(time.Millisecond)
+	}
+
+	during := runtime.NumGoroutine()
+	close(block)
+	if err := m.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	// Each routine runs as one goroutine (run() calls its Func inline), so the delta is about n; a
+	// per-routine AfterFunc watcher on top of that would double it to about 2n.
+	if delta := during - before; delta > n+n/2 {
+		t.Fatalf("goroutine delta = %d for %d routines that never called AfterFunc, want no extra per-routine watcher", delta, n)
+	}
+}
@@ -0,0 +1,325 @@
+package machine
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type scheduleKind int
+
+const (
+	scheduleCron scheduleKind = iota
+	scheduleInterval
+	scheduleSingleShot
+	scheduleUntilSuccess
+)
+
+type scheduleOpts struct {
+	kind     scheduleKind
+	cron     *cronSpec
+	interval time.Duration
+}
+
+// WithCron re-invokes the Func on every tick matching the given 5-field cron expression
+// (minute hour day-of-month month day-of-week). `*`, lists (`,`), steps (`/`) and ranges (`-`)
+// are supported. Each tick is dispatched as a new managed routine, subject to the Machine's
+// max, tags and middlewares, unless WithReusePID is also given.
+func WithCron(spec string) GoOpt {
+	cs, err := parseCron(spec)
+	if err != nil {
+		cs = &cronSpec{err: err}
+	}
+	return func(o *goOpts) {
+		o.schedule = &scheduleOpts{kind: scheduleCron, cron: cs}
+	}
+}
+
+// WithInterval re-invokes the Func every d, until the Machine's context is cancelled.
+func WithInterval(d time.Duration) GoOpt {
+	return func(o *goOpts) {
+		o.schedule = &scheduleOpts{kind: scheduleInterval, interval: d}
+	}
+}
+
+// WithSingleShotAfter fires the Func exactly once, after d has elapsed.
+func WithSingleShotAfter(d time.Duration) GoOpt {
+	return func(o *goOpts) {
+		o.schedule = &scheduleOpts{kind: scheduleSingleShot, interval: d}
+	}
+}
+
+// WithUntilSuccess retries the Func, waiting interval between attempts, until it returns a nil
+// error or the Machine's context is cancelled.
+func WithUntilSuccess(interval time.Duration) GoOpt {
+	return func(o *goOpts) {
+		o.schedule = &scheduleOpts{kind: scheduleUntilSuccess, interval: interval}
+	}
+}
+
+// WithReusePID causes a scheduled Func (WithCron, WithInterval, WithUntilSuccess) to reuse the
+// same PID across every tick instead of minting a new one for each invocation.
+func WithReusePID() GoOpt {
+	return func(o *goOpts) {
+		o.reusePID = true
+	}
+}
+
+// scheduleEntry is a single registered schedule, owning its next fire time and run history.
+type scheduleEntry struct {
+	opts *goOpts
+	fn   Func
+	next time.Time
+
+	mu       sync.Mutex
+	lastErr  error
+	runCount int
+}
+
+// scheduleHeap is a min-heap of scheduleEntry ordered by next fire time.
+type scheduleHeap []*scheduleEntry
+
+func (h scheduleHeap) Len() int           { return len(h) }
+func (h scheduleHeap) Less(i, j int) bool { return h[i].next.Before(h[j].next) }
+func (h scheduleHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *scheduleHeap) Push(x any)        { *h = append(*h, x.(*scheduleEntry)) }
+func (h *scheduleHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// scheduler owns a Machine's pending fire times and re-enqueues ticks onto workQueue.
+type scheduler struct {
+	machine *Machine
+	mu      sync.Mutex
+	heap    scheduleHeap
+	wake    chan struct{}
+	errored []ScheduleStats
+}
+
+func newScheduler(m *Machine) *scheduler {
+	return &scheduler{
+		machine: m,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// register schedules fn according to opts.schedule and starts it firing ticks. An invalid
+// WithCron spec never fires, but is still surfaced through Stats.Schedules so the registration
+// isn't silently dropped.
+func (s *scheduler) register(fn Func, opts *goOpts) {
+	if opts.schedule.kind == scheduleCron && opts.schedule.cron.err != nil {
+		s.mu.Lock()
+		s.errored = append(s.errored, ScheduleStats{LastErr: opts.schedule.cron.err})
+		s.mu.Unlock()
+		return
+	}
+	if opts.reusePID && opts.id == 0 {
+		opts.id = rand.Int()
+	}
+	e := &scheduleEntry{opts: opts, fn: fn}
+	switch opts.schedule.kind {
+	case scheduleCron:
+		e.next = opts.schedule.cron.next(time.Now())
+	default:
+		e.next = time.Now().Add(opts.schedule.interval)
+	}
+	s.mu.Lock()
+	heap.Push(&s.heap, e)
+	s.mu.Unlock()
+	s.poke()
+}
+
+func (s *scheduler) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run drives the scheduler until the Machine's context is cancelled.
+func (s *scheduler) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		wait := time.Hour
+		if len(s.heap) > 0 {
+			if w := time.Until(s.heap[0].next); w > 0 {
+				wait = w
+			} else {
+				wait = 0
+			}
+		}
+		s.mu.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-s.machine.ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+			s.fire()
+		}
+	}
+}
+
+// fire pops every entry due to run and dispatches a tick for each.
+func (s *scheduler) fire() {
+	now := time.Now()
+	var due []*scheduleEntry
+	s.mu.Lock()
+	for len(s.heap) > 0 && !s.heap[0].next.After(now) {
+		due = append(due, heap.Pop(&s.heap).(*scheduleEntry))
+	}
+	s.mu.Unlock()
+	for _, e := range due {
+		s.tick(e)
+	}
+}
+
+func (s *scheduler) tick(e *scheduleEntry) {
+	if s.machine.ctx.Err() != nil {
+		return
+	}
+	opts := *e.opts
+	opts.schedule = nil
+	if !opts.reusePID {
+		opts.id = 0
+	}
+	fn := e.fn
+	kind := e.opts.schedule.kind
+	interval := e.opts.schedule.interval
+	wrapped := func(r Routine) error {
+		err := fn(r)
+		e.mu.Lock()
+		e.runCount++
+		e.lastErr = err
+		e.mu.Unlock()
+		if kind == scheduleUntilSuccess && err != nil {
+			s.reschedule(e, time.Now().Add(interval))
+		}
+		return err
+	}
+	// dispatch blocks on the admission semaphore when the Machine is at capacity; hand it off so
+	// one busy schedule can't stall every other entry's fire/reschedule below.
+	go s.machine.dispatch(&work{opts: &opts, fn: wrapped})
+
+	switch kind {
+	case scheduleCron:
+		s.reschedule(e, e.opts.schedule.cron.next(time.Now()))
+	case scheduleInterval:
+		s.reschedule(e, time.Now().Add(interval))
+	case scheduleSingleShot, scheduleUntilSuccess:
+		// single shot never recurs; until-success reschedules itself from wrapped on failure.
+	}
+}
+
+func (s *scheduler) reschedule(e *scheduleEntry, next time.Time) {
+	e.next = next
+	s.mu.Lock()
+	heap.Push(&s.heap, e)
+	s.mu.Unlock()
+	s.poke()
+}
+
+// snapshot returns the current state of every pending schedule, plus any registration rejected
+// for an invalid WithCron spec, for Stats.
+func (s *scheduler) snapshot() []ScheduleStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ScheduleStats, 0, len(s.heap)+len(s.errored))
+	for _, e := range s.heap {
+		e.mu.Lock()
+		out = append(out, ScheduleStats{NextFire: e.next, LastErr: e.lastErr, RunCount: e.runCount})
+		e.mu.Unlock()
+	}
+	out = append(out, s.errored...)
+	return out
+}
+
+// cronSpec is a parsed 5-field cron expression (minute hour day-of-month month day-of-week).
+type cronSpec struct {
+	minute, hour, dom, month, dow cronFieldSet
+	err                           error
+}
+
+type cronFieldSet map[int]bool
+
+func parseCron(spec string) (*cronSpec, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("machine: cron spec %q must have 5 fields", spec)
+	}
+	ranges := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+	sets := make([]cronFieldSet, 5)
+	for i, f := range fields {
+		set, err := parseCronField(f, ranges[i][0], ranges[i][1])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	return &cronSpec{minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4]}, nil
+}
+
+func parseCronField(f string, min, max int) (cronFieldSet, error) {
+	set := cronFieldSet{}
+	for _, part := range strings.Split(f, ",") {
+		base, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			base = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("machine: invalid cron step %q", part)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if base != "*" {
+			if i := strings.IndexByte(base, '-'); i >= 0 {
+				a, err1 := strconv.Atoi(base[:i])
+				b, err2 := strconv.Atoi(base[i+1:])
+				if err1 != nil || err2 != nil {
+					return nil, fmt.Errorf("machine: invalid cron range %q", base)
+				}
+				lo, hi = a, b
+			} else {
+				n, err := strconv.Atoi(base)
+				if err != nil {
+					return nil, fmt.Errorf("machine: invalid cron field %q", base)
+				}
+				lo, hi = n, n
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// next returns the first minute-aligned time strictly after t that matches the spec.
+func (c *cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for i := 0; i < 366*24*60; i++ {
+		if c.minute[t.Minute()] && c.hour[t.Hour()] && c.dom[t.Day()] && c.month[int(t.Month())] && c.dow[int(t.Weekday())] {
+			return t
+		}
+		t = t.Add(time.Minute)
+	}
+	return t
+}
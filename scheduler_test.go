@@ -0,0 +1,62 @@
+package machine
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseCronEveryMinute(t *testing.T) {
+	cs, err := parseCron("* * * * *")
+	if err != nil {
+		t.Fatalf("parseCron: %v", err)
+	}
+	from := time.Date(2026, 1, 1, 12, 0, 30, 0, time.UTC)
+	got := cs.next(from)
+	want := time.Date(2026, 1, 1, 12, 1, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("next = %v, want %v", got, want)
+	}
+}
+
+func TestParseCronInvalidField(t *testing.T) {
+	if _, err := parseCron("* * *"); err == nil {
+		t.Fatal("expected error for cron spec with wrong field count")
+	}
+}
+
+func TestWithCronInvalidSpecSurfacedInStats(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	m.Go(func(Routine) error { return nil }, WithCron("bogus"))
+
+	stats := m.Stats()
+	if len(stats.Schedules) != 1 {
+		t.Fatalf("Schedules = %d entries, want 1", len(stats.Schedules))
+	}
+	if stats.Schedules[0].LastErr == nil {
+		t.Fatal("expected the rejected cron registration to carry its parse error")
+	}
+}
+
+func TestWithIntervalFires(t *testing.T) {
+	m := New(context.Background())
+	defer m.Close()
+
+	var count int64
+	m.Go(func(Routine) error {
+		atomic.AddInt64(&count, 1)
+		return nil
+	}, WithInterval(5*time.Millisecond))
+
+	deadline := time.After(2 * time.Second)
+	for atomic.LoadInt64(&count) < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("only %d ticks fired within the deadline", atomic.LoadInt64(&count))
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package machine
+
+import "time"
+
+// Stats is a snapshot of a Machine's managed goroutines at a point in time.
+type Stats struct {
+	TotalRoutines int
+	Routines      []RoutineStats
+	TotalChildren int
+	HasParent     bool
+	Schedules     []ScheduleStats
+}
+
+// RoutineStats describes a single managed goroutine.
+type RoutineStats struct {
+	PID       int
+	Start     time.Time
+	Duration  time.Duration
+	Tags      []string
+	Restarts  int
+	LastError error
+	LastPanic []byte
+}
+
+// ScheduleStats describes a scheduled (cron, interval, single-shot or until-success) Func.
+type ScheduleStats struct {
+	NextFire time.Time
+	LastErr  error
+	RunCount int
+}
@@ -0,0 +1,7 @@
+package machine
+
+// work is a unit of pending dispatch waiting to be picked up by serve().
+type work struct {
+	opts *goOpts
+	fn   Func
+}